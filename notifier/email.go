@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	"fmt"
+	"net/smtp"
+)
+
+// Email delivers order events as plain-text mail via an SMTP relay.
+type Email struct {
+	SMTPAddr string // host:port of the SMTP relay
+	Auth     smtp.Auth
+	From     string
+	To       []string
+}
+
+func (e Email) send(subject, body string) error {
+	msg := fmt.Sprintf("Subject: %s\r\n\r\n%s\r\n", subject, body)
+	return smtp.SendMail(e.SMTPAddr, e.Auth, e.From, e.To, []byte(msg))
+}
+
+func (e Email) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+	e.send(fmt.Sprintf("RedOctober order %s created", orderNum),
+		fmt.Sprintf(hipchatNewOrder, joinCSV(names), joinCSV(labels), uses, duration))
+}
+
+func (e Email) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	e.send(fmt.Sprintf("RedOctober order %s delegation", orderNum),
+		fmt.Sprintf(hipchatNewDelegation, delegator, joinCSV(labels), delegatee, orderNum, duration))
+}
+
+func (e Email) NotifyOrderFulfilled(name, orderNum string) {
+	e.send(fmt.Sprintf("RedOctober order %s fulfilled", orderNum),
+		fmt.Sprintf(hipchatOrderFulfilled, name, orderNum))
+}
+
+func (e Email) NotifyOrderExpired(name, orderNum string) {
+	e.send(fmt.Sprintf("RedOctober order %s expired", orderNum),
+		fmt.Sprintf(hipchatOrderExpired, name, orderNum))
+}
+
+func (e Email) NotifyPolicySatisfied(name, orderNum string) {
+	e.send(fmt.Sprintf("RedOctober order %s ready to redeem", orderNum),
+		fmt.Sprintf(hipchatPolicySatisfied, name, orderNum))
+}
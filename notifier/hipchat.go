@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/cloudflare/redoctober/hipchat"
+)
+
+const (
+	hipchatNewOrder        = "%s has created an order for the label %s. requesting %d delegations for %s"
+	hipchatNewOrderLink    = "@%s - https://%s?%s"
+	hipchatOrderFulfilled  = "%s has had order %s fulfilled."
+	hipchatNewDelegation   = "%s has delegated the label %s to %s (per order %s) for %s"
+	hipchatOrderExpired    = "%s's order %s expired before it was fulfilled."
+	hipchatPolicySatisfied = "%s's order %s has met its delegation policy and is ready to be redeemed."
+)
+
+// Hipchat notifies a Hipchat room via the existing hipchat.HipchatClient,
+// preserving the red/green/yellow/purple message coloring that Orderer
+// used before Notifier existed.
+type Hipchat struct {
+	Client hipchat.HipchatClient
+}
+
+func joinCSV(items []string) string {
+	list := ""
+	for i, item := range items {
+		if i == 0 {
+			list += item
+		} else {
+			// Never include spaces in something go URI encodes. Go will
+			// add a + to the string, instead of a %20
+			list += "," + item
+		}
+	}
+	return list
+}
+
+func (h Hipchat) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+	labelList := joinCSV(labels)
+	nameList := joinCSV(names)
+
+	n := fmt.Sprintf(hipchatNewOrder, nameList, labelList, uses, duration)
+	h.Client.Notify(n, hipchat.RedBackground)
+	for owner, hipchatName := range owners {
+		queryParams := url.Values{
+			"delegator": {owner},
+			"label":     {labelList},
+			"duration":  {duration},
+			"uses":      {strconv.Itoa(uses)},
+			"ordernum":  {orderNum},
+			"delegatee": {nameList},
+		}.Encode()
+		h.Client.Notify(fmt.Sprintf(hipchatNewOrderLink, hipchatName, h.Client.RoHost, queryParams), hipchat.GreenBackground)
+	}
+}
+
+func (h Hipchat) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	labelList := ""
+	for i, label := range labels {
+		if i == 0 {
+			labelList += label
+		} else {
+			labelList += ", " + label
+		}
+	}
+	n := fmt.Sprintf(hipchatNewDelegation, delegator, labelList, delegatee, orderNum, duration)
+	h.Client.Notify(n, hipchat.YellowBackground)
+}
+
+func (h Hipchat) NotifyOrderFulfilled(name, orderNum string) {
+	n := fmt.Sprintf(hipchatOrderFulfilled, name, orderNum)
+	h.Client.Notify(n, hipchat.PurpleBackground)
+}
+
+func (h Hipchat) NotifyOrderExpired(name, orderNum string) {
+	n := fmt.Sprintf(hipchatOrderExpired, name, orderNum)
+	h.Client.Notify(n, hipchat.RedBackground)
+}
+
+func (h Hipchat) NotifyPolicySatisfied(name, orderNum string) {
+	n := fmt.Sprintf(hipchatPolicySatisfied, name, orderNum)
+	h.Client.Notify(n, hipchat.PurpleBackground)
+}
@@ -0,0 +1,17 @@
+package notifier
+
+// NoOp is a Notifier that discards every event. It is useful as the
+// default in tests, or for deployments that don't want any external
+// notifications.
+type NoOp struct{}
+
+func (NoOp) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+}
+
+func (NoOp) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {}
+
+func (NoOp) NotifyOrderFulfilled(name, orderNum string) {}
+
+func (NoOp) NotifyOrderExpired(name, orderNum string) {}
+
+func (NoOp) NotifyPolicySatisfied(name, orderNum string) {}
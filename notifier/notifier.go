@@ -0,0 +1,73 @@
+// Package notifier defines a pluggable interface for delivering order
+// lifecycle messages to external systems, so that callers are not tied
+// to any single messaging backend.
+//
+// Copyright (c) 2016 CloudFlare, Inc.
+package notifier
+
+// Notifier delivers order lifecycle events. Implementations are expected
+// to be safe for concurrent use, since an Orderer may notify from many
+// request goroutines.
+type Notifier interface {
+	// NotifyNewOrder announces that a new order has been created and,
+	// where possible, gives each owner a direct link to act on it.
+	NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string)
+
+	// NotifyDelegation announces that an owner has delegated toward an
+	// order.
+	NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string)
+
+	// NotifyOrderFulfilled announces that an order has been completely
+	// delegated and redeemed.
+	NotifyOrderFulfilled(name, orderNum string)
+
+	// NotifyOrderExpired announces that an order's deadline passed
+	// before it was fulfilled, and that it has been removed.
+	NotifyOrderExpired(name, orderNum string)
+
+	// NotifyPolicySatisfied announces that an order's Policy has just
+	// been met for the first time, so approvers know it is ready to be
+	// redeemed.
+	NotifyPolicySatisfied(name, orderNum string)
+}
+
+// chain fans a notification out to every Notifier it holds. It lets
+// Orderer treat "no notifiers", "one notifier", and "several notifiers"
+// uniformly.
+type chain []Notifier
+
+func (c chain) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+	for _, n := range c {
+		n.NotifyNewOrder(duration, orderNum, names, labels, uses, owners)
+	}
+}
+
+func (c chain) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	for _, n := range c {
+		n.NotifyDelegation(delegator, delegatee, orderNum, duration, labels)
+	}
+}
+
+func (c chain) NotifyOrderFulfilled(name, orderNum string) {
+	for _, n := range c {
+		n.NotifyOrderFulfilled(name, orderNum)
+	}
+}
+
+func (c chain) NotifyOrderExpired(name, orderNum string) {
+	for _, n := range c {
+		n.NotifyOrderExpired(name, orderNum)
+	}
+}
+
+func (c chain) NotifyPolicySatisfied(name, orderNum string) {
+	for _, n := range c {
+		n.NotifyPolicySatisfied(name, orderNum)
+	}
+}
+
+// Chain combines several notifiers into one, so an Orderer can hold a
+// single Notifier even when messages must go to more than one backend.
+func Chain(notifiers ...Notifier) Notifier {
+	return chain(notifiers)
+}
@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Slack attachment colors mirroring the red/green/yellow/purple
+// semantics used by the Hipchat notifier.
+const (
+	slackDanger  = "danger"
+	slackGood    = "good"
+	slackWarning = "warning"
+	slackPurple  = "#764FA5"
+)
+
+type slackAttachment struct {
+	Text  string `json:"text"`
+	Color string `json:"color"`
+}
+
+type slackPayload struct {
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// Slack posts order events to a Slack incoming webhook as attachments,
+// colored the same way the Hipchat notifier colors its messages.
+type Slack struct {
+	// WebhookURL is the incoming webhook endpoint configured in Slack.
+	WebhookURL string
+
+	// Client sends the webhook requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+func (s Slack) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s Slack) post(text, color string) error {
+	body, err := json.Marshal(slackPayload{
+		Attachments: []slackAttachment{{Text: text, Color: color}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.client().Post(s.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notifier: slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s Slack) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+	labelList := joinCSV(labels)
+	nameList := joinCSV(names)
+	s.post(fmt.Sprintf(hipchatNewOrder, nameList, labelList, uses, duration), slackDanger)
+	for owner := range owners {
+		s.post(fmt.Sprintf("%s - order %s awaiting delegation for %s", owner, orderNum, labelList), slackGood)
+	}
+}
+
+func (s Slack) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	labelList := joinCSV(labels)
+	s.post(fmt.Sprintf(hipchatNewDelegation, delegator, labelList, delegatee, orderNum, duration), slackWarning)
+}
+
+func (s Slack) NotifyOrderFulfilled(name, orderNum string) {
+	s.post(fmt.Sprintf(hipchatOrderFulfilled, name, orderNum), slackPurple)
+}
+
+func (s Slack) NotifyOrderExpired(name, orderNum string) {
+	s.post(fmt.Sprintf(hipchatOrderExpired, name, orderNum), slackDanger)
+}
+
+func (s Slack) NotifyPolicySatisfied(name, orderNum string) {
+	s.post(fmt.Sprintf(hipchatPolicySatisfied, name, orderNum), slackPurple)
+}
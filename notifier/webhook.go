@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// webhookPayload is the JSON body POSTed to Webhook.URL for every order
+// event. Event distinguishes which lifecycle stage produced it.
+type webhookPayload struct {
+	Event      string   `json:"event"`
+	OrderNum   string   `json:"order_id"`
+	Actor      string   `json:"actor,omitempty"`
+	Labels     []string `json:"labels"`
+	Delegatees []string `json:"delegatees"`
+	Uses       int      `json:"uses,omitempty"`
+	Duration   string   `json:"duration,omitempty"`
+}
+
+// Webhook posts a generic JSON description of each order event to an
+// arbitrary HTTPS endpoint, for backends that don't warrant a
+// dedicated Notifier implementation.
+type Webhook struct {
+	URL string
+
+	// Client sends the webhook requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+func (w Webhook) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return http.DefaultClient
+}
+
+func (w Webhook) post(payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client().Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notifier: webhook %s returned %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+func (w Webhook) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
+	w.post(webhookPayload{
+		Event:      "new_order",
+		OrderNum:   orderNum,
+		Labels:     labels,
+		Delegatees: names,
+		Uses:       uses,
+		Duration:   duration,
+	})
+}
+
+func (w Webhook) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	w.post(webhookPayload{
+		Event:      "delegation",
+		OrderNum:   orderNum,
+		Actor:      delegator,
+		Labels:     labels,
+		Delegatees: []string{delegatee},
+		Duration:   duration,
+	})
+}
+
+func (w Webhook) NotifyOrderFulfilled(name, orderNum string) {
+	w.post(webhookPayload{
+		Event:    "order_fulfilled",
+		OrderNum: orderNum,
+		Actor:    name,
+	})
+}
+
+func (w Webhook) NotifyOrderExpired(name, orderNum string) {
+	w.post(webhookPayload{
+		Event:    "order_expired",
+		OrderNum: orderNum,
+		Actor:    name,
+	})
+}
+
+func (w Webhook) NotifyPolicySatisfied(name, orderNum string) {
+	w.post(webhookPayload{
+		Event:    "policy_satisfied",
+		OrderNum: orderNum,
+		Actor:    name,
+	})
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newWebhookTestServer(t *testing.T, got *webhookPayload) (Webhook, func()) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Errorf("decoding webhook body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return Webhook{URL: srv.URL}, srv.Close
+}
+
+func TestWebhookOrderFulfilledDoesNotClaimAFakeDelegatee(t *testing.T) {
+	var got webhookPayload
+	w, closeSrv := newWebhookTestServer(t, &got)
+	defer closeSrv()
+
+	w.NotifyOrderFulfilled("alice", "order-1")
+
+	if len(got.Delegatees) != 0 {
+		t.Fatalf("Delegatees = %v, want none; the fulfilling user is not a delegatee", got.Delegatees)
+	}
+	if got.Actor != "alice" {
+		t.Fatalf("Actor = %q, want %q", got.Actor, "alice")
+	}
+}
+
+func TestWebhookOrderExpiredDoesNotClaimAFakeDelegatee(t *testing.T) {
+	var got webhookPayload
+	w, closeSrv := newWebhookTestServer(t, &got)
+	defer closeSrv()
+
+	w.NotifyOrderExpired("creator", "order-1")
+
+	if len(got.Delegatees) != 0 {
+		t.Fatalf("Delegatees = %v, want none; the order's creator is not a delegatee", got.Delegatees)
+	}
+	if got.Actor != "creator" {
+		t.Fatalf("Actor = %q, want %q", got.Actor, "creator")
+	}
+}
+
+func TestWebhookDelegationReportsTheRealDelegatee(t *testing.T) {
+	var got webhookPayload
+	w, closeSrv := newWebhookTestServer(t, &got)
+	defer closeSrv()
+
+	w.NotifyDelegation("bob", "carol", "order-1", "1h", []string{"prod"})
+
+	if len(got.Delegatees) != 1 || got.Delegatees[0] != "carol" {
+		t.Fatalf("Delegatees = %v, want [carol]", got.Delegatees)
+	}
+	if got.Actor != "bob" {
+		t.Fatalf("Actor = %q, want %q", got.Actor, "bob")
+	}
+}
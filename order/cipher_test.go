@@ -0,0 +1,55 @@
+package order
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAESCipherRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	c, err := NewAESCipher(key)
+	if err != nil {
+		t.Fatalf("NewAESCipher: %v", err)
+	}
+
+	plaintext := []byte("bob,carol,dave")
+	ciphertext, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatalf("ciphertext contains plaintext in the clear: %q", ciphertext)
+	}
+
+	got, err := c.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt(Encrypt(x)) = %q, want %q", got, plaintext)
+	}
+}
+
+func TestAESCipherRejectsBadKeyLength(t *testing.T) {
+	if _, err := NewAESCipher([]byte("too-short")); err == nil {
+		t.Fatal("NewAESCipher with a 9-byte key should have failed")
+	}
+}
+
+func TestAESCipherDetectsTampering(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	c, err := NewAESCipher(key)
+	if err != nil {
+		t.Fatalf("NewAESCipher: %v", err)
+	}
+
+	ciphertext, err := c.Encrypt([]byte("bob,carol,dave"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 0xff
+
+	if _, err := c.Decrypt(ciphertext); err == nil {
+		t.Fatal("Decrypt of tampered ciphertext should have failed")
+	}
+}
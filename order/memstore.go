@@ -0,0 +1,88 @@
+package order
+
+import (
+	"context"
+	"sync"
+)
+
+// MemStore is the default OrderStore: orders live only in memory and
+// don't survive a restart, matching Orderer's original behavior.
+type MemStore struct {
+	mu     sync.RWMutex
+	orders map[string]Order
+	subs   []chan Event
+}
+
+// NewMemStore creates an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{orders: make(map[string]Order)}
+}
+
+func (s *MemStore) Get(orderNum string) (Order, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ord, ok := s.orders[orderNum]
+	return ord, ok, nil
+}
+
+func (s *MemStore) Put(orderNum string, ord Order) error {
+	s.mu.Lock()
+	s.orders[orderNum] = ord
+	s.mu.Unlock()
+	s.broadcast(Event{OrderNum: orderNum, Order: ord})
+	return nil
+}
+
+func (s *MemStore) Delete(orderNum string) error {
+	s.mu.Lock()
+	delete(s.orders, orderNum)
+	s.mu.Unlock()
+	s.broadcast(Event{OrderNum: orderNum, Deleted: true})
+	return nil
+}
+
+func (s *MemStore) List() ([]Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Order, 0, len(s.orders))
+	for _, ord := range s.orders {
+		out = append(out, ord)
+	}
+	return out, nil
+}
+
+func (s *MemStore) Watch(ctx context.Context) <-chan Event {
+	ch := make(chan Event, 16)
+
+	s.mu.Lock()
+	s.subs = append(s.subs, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, c := range s.subs {
+			if c == ch {
+				s.subs = append(s.subs[:i], s.subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (s *MemStore) broadcast(e Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, ch := range s.subs {
+		select {
+		case ch <- e:
+		default:
+			// A slow subscriber shouldn't block order mutations; it
+			// simply misses this event.
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package order
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	ordersOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redoctober_orders_open",
+		Help: "Number of orders currently pending fulfillment.",
+	})
+
+	ordersCreatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redoctober_orders_created_total",
+		Help: "Total number of orders created.",
+	}, []string{"labels", "creator"})
+
+	ordersFulfilledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redoctober_orders_fulfilled_total",
+		Help: "Total number of orders fulfilled.",
+	}, []string{"labels", "creator"})
+
+	ordersExpiredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redoctober_orders_expired_total",
+		Help: "Total number of orders that expired before being fulfilled.",
+	}, []string{"labels", "creator"})
+
+	orderFulfillmentSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "redoctober_order_fulfillment_seconds",
+		Help:    "Time between an order being requested and fulfilled.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// labelSet renders an order's labels as a single Prometheus label
+// value, since label cardinality must be bounded ahead of time.
+func labelSet(labels []string) string {
+	return strings.Join(labels, ",")
+}
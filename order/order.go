@@ -6,21 +6,14 @@
 package order
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
-	"net/url"
-	"strconv"
+	"sync"
 	"time"
 
-	"github.com/cloudflare/redoctober/hipchat"
-)
-
-const (
-	NewOrder       = "%s has created an order for the label %s. requesting %d delegations for %s"
-	NewOrderLink   = "@%s - https://%s?%s"
-	OrderFulfilled = "%s has had order %s fulfilled."
-	NewDelegation  = "%s has delegated the label %s to %s (per order %s) for %s"
+	"github.com/cloudflare/redoctober/notifier"
+	"github.com/cloudflare/redoctober/orderaudit"
 )
 
 type Order struct {
@@ -34,6 +27,10 @@ type Order struct {
 	OwnersDelegated   []string
 	Owners            []string
 	Labels            []string
+
+	// Policy is the quorum OwnersDelegated must meet before the order
+	// is fulfillable. The zero Policy requires every one of Owners.
+	Policy Policy
 }
 
 type OrderIndex struct {
@@ -43,14 +40,57 @@ type OrderIndex struct {
 	OrderOwners []string
 }
 
-// Orders represents a mapping of Order IDs to Orders. This structure
-// is useful for looking up information about individual Orders and
-// whether or not an order has been fulfilled. Orders that have been
-// fulfilled will be removed from the structure.
+// Orderer manages the set of pending orders, backed by a pluggable
+// OrderStore so that orders can outlive a single process.
+// Orders that have been fulfilled are removed from the store. All
+// exported methods are safe to call concurrently: mu guards both the
+// timer bookkeeping and the read-then-write sequences (like "look up,
+// then mutate") that span more than one store call.
 type Orderer struct {
-	Orders        map[string]Order
-	Hipchat       hipchat.HipchatClient
+	Notifier      notifier.Notifier
+	Audit         orderaudit.Sink
 	AlternateName string
+
+	store       OrderStore
+	mu          sync.RWMutex
+	timers      map[string]*orderTimer
+	watchCancel context.CancelFunc
+}
+
+// remainingNeeded reports how many more distinct owners must delegate
+// before ord's Policy is satisfied. It is at least 1 for as long as
+// ord.Policy.Satisfied reports false, even once the raw threshold count
+// is met, since RequiredOwners and MinDistinctGroups can still be
+// outstanding.
+func remainingNeeded(ord Order) int {
+	threshold := ord.Policy.Threshold
+	if threshold == 0 {
+		threshold = len(ord.Owners)
+	}
+	remaining := threshold - len(ord.OwnersDelegated)
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining == 0 && !ord.Policy.Satisfied(ord) {
+		return 1
+	}
+	return remaining
+}
+
+// currentTime exists so that methods whose parameter lists shadow the
+// "time" package (e.g. UpdateOrders's "time string" argument) can still
+// stamp an audit Event.
+func currentTime() time.Time {
+	return time.Now()
+}
+
+// orderTimer tracks the expiration timer for a single order, following
+// the same pattern as the netstack deadlineTimer: a *time.Timer paired
+// with a cancelCh that lets a callback already in flight tell whether
+// it was superseded before it could acquire the Orderer's lock.
+type orderTimer struct {
+	timer    *time.Timer
+	cancelCh chan struct{}
 }
 
 func CreateOrder(name, orderNum string, time time.Time, duration time.Duration, adminsDelegated, contacts, users, labels []string, numDelegated int) (ord Order) {
@@ -66,81 +106,301 @@ func CreateOrder(name, orderNum string, time time.Time, duration time.Duration,
 	return
 }
 
+// CreateOrderWithPolicy is CreateOrder with an explicit Policy, for
+// callers that need something other than the default "every owner must
+// delegate" quorum. CreateOrder remains available for backward
+// compatibility and is equivalent to CreateOrderWithPolicy with the
+// zero Policy.
+func CreateOrderWithPolicy(name, orderNum string, created time.Time, duration time.Duration, adminsDelegated, contacts, users, labels []string, numDelegated int, policy Policy) (ord Order) {
+	ord = CreateOrder(name, orderNum, created, duration, adminsDelegated, contacts, users, labels, numDelegated)
+	ord.Policy = policy
+	return
+}
+
 func GenerateNum() (num string) {
 	b := make([]byte, 12)
 	rand.Read(b)
 	return hex.EncodeToString(b)
 }
 
-// NewOrder will create a new map of Orders
-func NewOrderer(hipchatClient hipchat.HipchatClient) (o Orderer) {
-	o.Orders = make(map[string]Order)
-	o.Hipchat = hipchatClient
-	o.AlternateName = "HipchatName"
-	return
+// NewOrderer creates an Orderer backed by an in-memory MemStore,
+// matching the original behavior where orders did not survive a restart.
+// notifiers are chained, so an event is delivered to every one of them;
+// pass none to get an Orderer that notifies no one.
+func NewOrderer(notifiers ...notifier.Notifier) *Orderer {
+	return NewOrdererWithStore(NewMemStore(), notifiers...)
+}
+
+// NewOrdererWithStore creates an Orderer backed by store, for callers
+// that want orders to persist across restarts or be shared across a
+// cluster (see the orderstore package). It re-arms expiration timers
+// for every order already in store, and watches store for orders
+// created or updated by a peer sharing it, so that neither a process
+// restart nor another node's activity leaves an order's timer unset.
+// Call Close when the Orderer is no longer needed to stop that watch.
+func NewOrdererWithStore(store OrderStore, notifiers ...notifier.Notifier) *Orderer {
+	o := &Orderer{
+		store:         store,
+		Notifier:      notifier.Chain(notifiers...),
+		Audit:         orderaudit.NoOp{},
+		AlternateName: "HipchatName",
+		timers:        make(map[string]*orderTimer),
+	}
+
+	o.mu.Lock()
+	o.rearmTimers()
+	o.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.watchCancel = cancel
+	go o.watchStore(ctx)
+
+	return o
 }
 
-// notify is a generic function for using a notifier, but it checks to make
-// sure that there is a notifier available, since there won't always be.
-func notify(o *Orderer, msg, color string) {
-	o.Hipchat.Notify(msg, color)
+// Close stops watching store for changes made by other processes
+// sharing it. It does not close store itself.
+func (o *Orderer) Close() {
+	o.watchCancel()
 }
+
 func (o *Orderer) NotifyNewOrder(duration, orderNum string, names, labels []string, uses int, owners map[string]string) {
-	labelList := ""
-	for i, label := range labels {
-		if i == 0 {
-			labelList += label
-		} else {
-			// Never include spaces in something go URI encodes. Go will
-			// add a + to the string, instead of a %20
-			labelList += "," + label
+	o.Notifier.NotifyNewOrder(duration, orderNum, names, labels, uses, owners)
+}
+
+func (o *Orderer) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
+	o.Notifier.NotifyDelegation(delegator, delegatee, orderNum, duration, labels)
+}
+
+func (o *Orderer) NotifyOrderFulfilled(name, orderNum string) {
+	o.Notifier.NotifyOrderFulfilled(name, orderNum)
+}
+
+func (o *Orderer) NotifyOrderExpired(name, orderNum string) {
+	o.Notifier.NotifyOrderExpired(name, orderNum)
+}
+
+func (o *Orderer) NotifyPolicySatisfied(name, orderNum string) {
+	o.Notifier.NotifyPolicySatisfied(name, orderNum)
+}
+
+// CreateOrder builds an Order from the given parameters, stores it under
+// orderNum, and - unless duration is zero, which means "no expiration"
+// for backward compatibility - arms a timer that will remove the order
+// and fire NotifyOrderExpired if it isn't fulfilled or canceled first.
+// It is equivalent to CreateOrderWithPolicy with the zero Policy.
+func (o *Orderer) CreateOrder(name, orderNum string, created time.Time, duration time.Duration, adminsDelegated, contacts, users, labels []string, numDelegated int) (string, error) {
+	return o.CreateOrderWithPolicy(name, orderNum, created, duration, adminsDelegated, contacts, users, labels, numDelegated, Policy{})
+}
+
+// CreateOrderWithPolicy is CreateOrder with an explicit Policy, for
+// callers that need something other than the default "every owner must
+// delegate" quorum.
+func (o *Orderer) CreateOrderWithPolicy(name, orderNum string, created time.Time, duration time.Duration, adminsDelegated, contacts, users, labels []string, numDelegated int, policy Policy) (string, error) {
+	ord := CreateOrderWithPolicy(name, orderNum, created, duration, adminsDelegated, contacts, users, labels, numDelegated, policy)
+
+	o.mu.Lock()
+	if err := o.store.Put(orderNum, ord); err != nil {
+		o.mu.Unlock()
+		return "", err
+	}
+	o.armExpiration(orderNum, duration)
+	o.mu.Unlock()
+
+	ordersOpen.Inc()
+	ordersCreatedTotal.WithLabelValues(labelSet(labels), name).Inc()
+	o.Audit.Record(orderaudit.Event{
+		OrderNum:        orderNum,
+		Actor:           name,
+		Action:          orderaudit.ActionCreated,
+		Labels:          labels,
+		Delegatees:      users,
+		Timestamp:       created,
+		RemainingNeeded: remainingNeeded(ord),
+	})
+
+	return orderNum, nil
+}
+
+// armExpiration starts the expiration timer for orderNum. The caller
+// must hold o.mu. A duration of zero means the order never expires.
+func (o *Orderer) armExpiration(orderNum string, duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	o.armExpirationAt(orderNum, time.Now().Add(duration))
+}
+
+// armExpirationAt starts orderNum's expiration timer so that it fires
+// at deadline, which may already be in the past - in which case it
+// fires on the next scheduler tick. The caller must hold o.mu.
+func (o *Orderer) armExpirationAt(orderNum string, deadline time.Time) {
+	cancelCh := make(chan struct{})
+	ot := &orderTimer{cancelCh: cancelCh}
+	ot.timer = time.AfterFunc(time.Until(deadline), func() {
+		o.expireOrder(orderNum, cancelCh)
+	})
+	o.timers[orderNum] = ot
+}
+
+// rearmTimers arms an expiration timer for every order already in
+// o.store that has a duration and isn't already armed, so that orders
+// created before a restart still expire on schedule. The caller must
+// hold o.mu.
+func (o *Orderer) rearmTimers() error {
+	orders, err := o.store.List()
+	if err != nil {
+		return err
+	}
+	for _, ord := range orders {
+		if ord.DurationRequested <= 0 {
+			continue
+		}
+		if _, armed := o.timers[ord.Num]; armed {
+			continue
 		}
+		o.armExpirationAt(ord.Num, ord.TimeRequested.Add(ord.DurationRequested))
 	}
-	nameList := ""
-	for i, name := range names {
-		if i == 0 {
-			nameList += name
-		} else {
-			// Never include spaces in something go URI encodes. Go will
-			// add a + to the string, instead of a %20
-			nameList += "," + name
+	return nil
+}
+
+// watchStore arms an expiration timer for every order.Event store
+// emits for a peer's create or update, so an order created on another
+// node sharing store still expires here even though this process never
+// called CreateOrder for it. It returns once ctx is done.
+func (o *Orderer) watchStore(ctx context.Context) {
+	for ev := range o.store.Watch(ctx) {
+		o.mu.Lock()
+		if ev.Deleted {
+			o.stopTimer(ev.OrderNum)
+		} else if ev.Order.DurationRequested > 0 {
+			if _, armed := o.timers[ev.OrderNum]; !armed {
+				o.armExpirationAt(ev.OrderNum, ev.Order.TimeRequested.Add(ev.Order.DurationRequested))
+			}
 		}
+		o.mu.Unlock()
 	}
+}
 
-	n := fmt.Sprintf(NewOrder, nameList, labelList, uses, duration)
-	notify(o, n, hipchat.RedBackground)
-	for owner, hipchatName := range owners {
-		queryParams := url.Values{
-			"delegator": {owner},
-			"label":     {labelList},
-			"duration":  {duration},
-			"uses":      {strconv.Itoa(uses)},
-			"ordernum":  {orderNum},
-			"delegatee": {nameList},
-		}.Encode()
-		notify(o, fmt.Sprintf(NewOrderLink, hipchatName, o.Hipchat.RoHost, queryParams), hipchat.GreenBackground)
+// expireOrder is the AfterFunc callback armed by armExpiration. cancelCh
+// is the channel that was current when the timer was (re)armed; if
+// ExtendOrder or CancelOrder raced ahead and replaced it, this callback
+// is stale and does nothing.
+func (o *Orderer) expireOrder(orderNum string, cancelCh chan struct{}) {
+	o.mu.Lock()
+	ot, armed := o.timers[orderNum]
+	if !armed || ot.cancelCh != cancelCh {
+		o.mu.Unlock()
+		return
 	}
+	delete(o.timers, orderNum)
+
+	ord, exists, err := o.store.Get(orderNum)
+	if err != nil || !exists {
+		o.mu.Unlock()
+		return
+	}
+	err = o.store.Delete(orderNum)
+	o.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	ordersOpen.Dec()
+	ordersExpiredTotal.WithLabelValues(labelSet(ord.Labels), ord.Creator).Inc()
+	o.Audit.Record(orderaudit.Event{
+		OrderNum:        orderNum,
+		Actor:           ord.Creator,
+		Action:          orderaudit.ActionExpired,
+		Labels:          ord.Labels,
+		Timestamp:       time.Now(),
+		RemainingNeeded: remainingNeeded(ord),
+	})
+
+	o.NotifyOrderExpired(ord.Creator, orderNum)
 }
 
-func (o *Orderer) NotifyDelegation(delegator, delegatee, orderNum, duration string, labels []string) {
-	labelList := ""
-	for i, label := range labels {
-		if i == 0 {
-			labelList += label
-		} else {
-			labelList += ", " + label
-		}
+// stopTimer stops and forgets orderNum's expiration timer, if any. The
+// caller must hold o.mu.
+func (o *Orderer) stopTimer(orderNum string) {
+	ot, armed := o.timers[orderNum]
+	if !armed {
+		return
+	}
+	if !ot.timer.Stop() {
+		// The timer already fired and expireOrder is racing to acquire
+		// o.mu. Close its cancelCh; once the entry below is removed (or
+		// replaced by a re-armed timer with a fresh cancelCh),
+		// expireOrder recognizes it is stale.
+		close(ot.cancelCh)
 	}
-	n := fmt.Sprintf(NewDelegation, delegator, labelList, delegatee, orderNum, duration)
-	notify(o, n, hipchat.YellowBackground)
+	delete(o.timers, orderNum)
 }
-func (o *Orderer) NotifyOrderFulfilled(name, orderNum string) {
-	n := fmt.Sprintf(OrderFulfilled, name, orderNum)
-	notify(o, n, hipchat.PurpleBackground)
+
+// ExtendOrder pushes orderNum's expiration back by extra, relative to
+// now. It reports whether the order was found and still pending.
+func (o *Orderer) ExtendOrder(orderNum string, extra time.Duration) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, exists, err := o.store.Get(orderNum)
+	if err != nil || !exists {
+		return false, err
+	}
+	o.stopTimer(orderNum)
+	o.armExpiration(orderNum, extra)
+
+	return true, nil
+}
+
+// CancelOrder removes orderNum without notifying that it expired. It
+// reports whether the order was found.
+func (o *Orderer) CancelOrder(orderNum string) (bool, error) {
+	o.mu.Lock()
+	ord, exists, err := o.store.Get(orderNum)
+	if err != nil || !exists {
+		o.mu.Unlock()
+		return false, err
+	}
+	o.stopTimer(orderNum)
+	err = o.store.Delete(orderNum)
+	o.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	ordersOpen.Dec()
+	o.Audit.Record(orderaudit.Event{
+		OrderNum:        orderNum,
+		Actor:           ord.Creator,
+		Action:          orderaudit.ActionCanceled,
+		Labels:          ord.Labels,
+		Timestamp:       time.Now(),
+		RemainingNeeded: remainingNeeded(ord),
+	})
+
+	return true, nil
+}
+
+// ListOrders returns every order currently pending, in no particular
+// order. It replaces the baseline's public Orders field now that orders
+// live behind the OrderStore interface.
+func (o *Orderer) ListOrders() ([]Order, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.store.List()
 }
 
 func (o *Orderer) FindOrder(user string, labels []string) (string, bool) {
-	for key, order := range o.Orders {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	orders, err := o.store.List()
+	if err != nil {
+		return "", false
+	}
+	for _, order := range orders {
 		foundLabel := false
 		foundUser := false
 		for _, orderUser := range order.Users {
@@ -162,11 +422,20 @@ func (o *Orderer) FindOrder(user string, labels []string) (string, bool) {
 		if !foundLabel {
 			continue
 		}
-		return key, true
+		return order.Num, true
 	}
 	return "", false
 }
 
+func containsOwner(owners []string, owner string) bool {
+	for _, o := range owners {
+		if o == owner {
+			return true
+		}
+	}
+	return false
+}
+
 func  intersect(a []string,b []string) []string {
 	if len(a) == 0 || len(b) == 0 {
 		return []string{"Any"};
@@ -183,45 +452,162 @@ func  intersect(a []string,b []string) []string {
 }
 
 
+// delegationEffect is a side effect of UpdateOrders to fire once o.mu
+// has been released, so a slow Audit.Record or Notifier call can't
+// stall every other Orderer method.
+type delegationEffect struct {
+	key            string
+	labels         []string
+	delegatees     []string
+	remaining      int
+	creator        string
+	policyNewlyMet bool
+	rejected       bool
+}
+
 func (o *Orderer) UpdateOrders(owner string, time string,  users []string, labels []string) (err error) {
 	owners := []string{owner}
-	for key, order := range o.Orders {
+
+	var effects []delegationEffect
+
+	o.mu.Lock()
+	orders, err := o.store.List()
+	if err != nil {
+		o.mu.Unlock()
+		return err
+	}
+	for _, order := range orders {
+		key := order.Num
 		common_owners := intersect(owners, order.Owners)
 		common_users := intersect(users, order.Users)
 		common_labels := intersect(labels, order.Labels)
 		if len(common_owners) > 0 &&
 		   len(common_users) > 0 &&
 		   len(common_labels) > 0 {
-			if len(order.OwnersDelegated) == 0 {
+			if order.Policy.Forbids(owner) {
+				effects = append(effects, delegationEffect{
+					key:       key,
+					labels:    common_labels,
+					remaining: remainingNeeded(order),
+					creator:   order.Creator,
+					rejected:  true,
+				})
+				continue
+			}
+			wasSatisfied := order.Policy.Satisfied(order)
+			if !containsOwner(order.OwnersDelegated, owner) {
 				order.OwnersDelegated = append(order.OwnersDelegated, owner)
 				order.Delegated++
-			} else {
-				for _, delegated := range order.OwnersDelegated {
-					if delegated == owner {
-						continue
-					}
-					order.OwnersDelegated = append(order.OwnersDelegated, owner)
-					order.Delegated++
-				}
 			}
-			o.Orders[key] = order
-			for _, delegatedUser := range common_users {
-				o.NotifyDelegation(owner, delegatedUser, key, time, common_labels)
+			if err := o.store.Put(key, order); err != nil {
+				o.mu.Unlock()
+				return err
 			}
+			effects = append(effects, delegationEffect{
+				key:            key,
+				labels:         common_labels,
+				delegatees:     common_users,
+				remaining:      remainingNeeded(order),
+				creator:        order.Creator,
+				policyNewlyMet: !wasSatisfied && order.Policy.Satisfied(order),
+			})
+		}
+	}
+	o.mu.Unlock()
+
+	for _, e := range effects {
+		if e.rejected {
+			o.Audit.Record(orderaudit.Event{
+				OrderNum:        e.key,
+				Actor:           owner,
+				Action:          orderaudit.ActionRejected,
+				Labels:          e.labels,
+				Timestamp:       currentTime(),
+				RemainingNeeded: e.remaining,
+			})
+			continue
+		}
+		o.Audit.Record(orderaudit.Event{
+			OrderNum:        e.key,
+			Actor:           owner,
+			Action:          orderaudit.ActionDelegated,
+			Labels:          e.labels,
+			Delegatees:      e.delegatees,
+			Timestamp:       currentTime(),
+			RemainingNeeded: e.remaining,
+		})
+		for _, delegatedUser := range e.delegatees {
+			o.NotifyDelegation(owner, delegatedUser, e.key, time, e.labels)
+		}
+		if e.policyNewlyMet {
+			o.NotifyPolicySatisfied(e.creator, e.key)
 		}
 	}
 	return nil
 }
 
+// fulfillmentEffect is a side effect of FulfillOrders to fire once o.mu
+// has been released; see delegationEffect.
+type fulfillmentEffect struct {
+	key        string
+	labels     []string
+	creator    string
+	delegatees []string
+	requested  time.Time
+}
+
 func (o *Orderer) FulfillOrders(user string, owners []string, labels []string) (err error) {
 	users := []string{user}
-	for key, order := range o.Orders {
+
+	var effects []fulfillmentEffect
+
+	o.mu.Lock()
+	orders, err := o.store.List()
+	if err != nil {
+		o.mu.Unlock()
+		return err
+	}
+	for _, order := range orders {
+		key := order.Num
 		if len(intersect(owners, order.Owners))  == len(owners) &&
 		   len(intersect(users, order.Users)) > 0 &&
 		   len(intersect(labels, order.Labels)) > 0 {
-			delete(o.Orders, key)
-			o.NotifyOrderFulfilled(user, key)
+			if !order.Policy.Satisfied(order) {
+				// The delegation quorum hasn't been met yet; refuse to
+				// redeem the order until it has.
+				continue
+			}
+			o.stopTimer(key)
+			if err := o.store.Delete(key); err != nil {
+				o.mu.Unlock()
+				return err
+			}
+			effects = append(effects, fulfillmentEffect{
+				key:        key,
+				labels:     order.Labels,
+				creator:    order.Creator,
+				delegatees: order.OwnersDelegated,
+				requested:  order.TimeRequested,
+			})
 		}
 	}
+	o.mu.Unlock()
+
+	for _, e := range effects {
+		ordersOpen.Dec()
+		ordersFulfilledTotal.WithLabelValues(labelSet(e.labels), e.creator).Inc()
+		orderFulfillmentSeconds.Observe(time.Since(e.requested).Seconds())
+		o.Audit.Record(orderaudit.Event{
+			OrderNum:        e.key,
+			Actor:           user,
+			Action:          orderaudit.ActionFulfilled,
+			Labels:          e.labels,
+			Delegatees:      e.delegatees,
+			Timestamp:       time.Now(),
+			RemainingNeeded: 0,
+		})
+
+		o.NotifyOrderFulfilled(user, e.key)
+	}
 	return nil
 }
@@ -0,0 +1,307 @@
+package order
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/redoctober/notifier"
+	"github.com/cloudflare/redoctober/orderaudit"
+)
+
+// auditSpy collects every Event it is handed, so tests can assert on
+// what gets written to the audit trail.
+type auditSpy struct {
+	mu     sync.Mutex
+	events []orderaudit.Event
+}
+
+func (a *auditSpy) Record(e orderaudit.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+// policySpy embeds notifier.NoOp so it only needs to override the
+// events these tests care about, while still satisfying
+// notifier.Notifier.
+type policySpy struct {
+	notifier.NoOp
+
+	mu              sync.Mutex
+	policySatisfied []string
+	orderFulfilled  []string
+	orderExpired    []string
+}
+
+func (p *policySpy) NotifyPolicySatisfied(name, orderNum string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policySatisfied = append(p.policySatisfied, orderNum)
+}
+
+func (p *policySpy) NotifyOrderFulfilled(name, orderNum string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orderFulfilled = append(p.orderFulfilled, orderNum)
+}
+
+func (p *policySpy) NotifyOrderExpired(name, orderNum string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orderExpired = append(p.orderExpired, orderNum)
+}
+
+// TestOrdererConcurrent hammers CreateOrder, FindOrder, UpdateOrders,
+// and FulfillOrders from many goroutines at once. Run with -race to
+// catch data races in Orderer's bookkeeping.
+func TestOrdererConcurrent(t *testing.T) {
+	o := NewOrderer(notifier.NoOp{})
+	defer o.Close()
+
+	const goroutines = 50
+	const ordersPerGoroutine = 20
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < ordersPerGoroutine; i++ {
+				orderNum := GenerateNum()
+				users := []string{"alice"}
+				labels := []string{"prod"}
+				owners := []string{"bob", "carol"}
+
+				if _, err := o.CreateOrder("creator", orderNum, time.Now(), time.Hour, nil, owners, users, labels, 2); err != nil {
+					t.Errorf("CreateOrder: %v", err)
+					continue
+				}
+
+				o.FindOrder("alice", labels)
+
+				if err := o.UpdateOrders("bob", "1h", users, labels); err != nil {
+					t.Errorf("UpdateOrders: %v", err)
+				}
+				if err := o.UpdateOrders("carol", "1h", users, labels); err != nil {
+					t.Errorf("UpdateOrders: %v", err)
+				}
+
+				if err := o.FulfillOrders("alice", owners, labels); err != nil {
+					t.Errorf("FulfillOrders: %v", err)
+				}
+
+				if _, err := o.CancelOrder(orderNum); err != nil {
+					t.Errorf("CancelOrder: %v", err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestUpdateOrdersNoDuplicateDelegation guards against the bug where
+// UpdateOrders incremented Delegated once per existing OwnersDelegated
+// entry instead of once per distinct new owner.
+func TestUpdateOrdersNoDuplicateDelegation(t *testing.T) {
+	o := NewOrderer(notifier.NoOp{})
+	defer o.Close()
+	users := []string{"alice"}
+	labels := []string{"prod"}
+	owners := []string{"bob", "carol", "dave"}
+
+	orderNum, err := o.CreateOrder("creator", GenerateNum(), time.Now(), time.Hour, nil, owners, users, labels, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	for _, owner := range owners {
+		if err := o.UpdateOrders(owner, "1h", users, labels); err != nil {
+			t.Fatalf("UpdateOrders(%s): %v", owner, err)
+		}
+	}
+
+	key, ok := o.FindOrder("alice", labels)
+	if !ok || key != orderNum {
+		t.Fatalf("FindOrder returned (%q, %v), want (%q, true)", key, ok, orderNum)
+	}
+
+	ord, exists, err := o.store.Get(orderNum)
+	if err != nil || !exists {
+		t.Fatalf("store.Get(%q) = (_, %v, %v)", orderNum, exists, err)
+	}
+	if ord.Delegated != len(owners) {
+		t.Fatalf("Delegated = %d, want %d", ord.Delegated, len(owners))
+	}
+
+	// Re-delegating the same owner must not count again.
+	if err := o.UpdateOrders("bob", "1h", users, labels); err != nil {
+		t.Fatalf("UpdateOrders(bob) again: %v", err)
+	}
+	ord, _, err = o.store.Get(orderNum)
+	if err != nil {
+		t.Fatalf("store.Get(%q): %v", orderNum, err)
+	}
+	if ord.Delegated != len(owners) {
+		t.Fatalf("Delegated after re-delegation = %d, want %d", ord.Delegated, len(owners))
+	}
+}
+
+// TestFulfillOrdersRequiresPolicy checks that FulfillOrders refuses to
+// redeem an order whose Policy isn't yet satisfied, even once every
+// invited owner has matched, and that it fulfills once the threshold is
+// reached.
+func TestFulfillOrdersRequiresPolicy(t *testing.T) {
+	spy := &policySpy{}
+	o := NewOrderer(spy)
+	defer o.Close()
+	users := []string{"alice"}
+	labels := []string{"prod"}
+	owners := []string{"bob", "carol", "dave"}
+	policy := Policy{Threshold: 2}
+
+	orderNum, err := o.CreateOrderWithPolicy("creator", GenerateNum(), time.Now(), time.Hour, nil, owners, users, labels, 0, policy)
+	if err != nil {
+		t.Fatalf("CreateOrderWithPolicy: %v", err)
+	}
+
+	if err := o.UpdateOrders("bob", "1h", users, labels); err != nil {
+		t.Fatalf("UpdateOrders(bob): %v", err)
+	}
+	if err := o.FulfillOrders("alice", owners, labels); err != nil {
+		t.Fatalf("FulfillOrders: %v", err)
+	}
+	if _, exists, _ := o.store.Get(orderNum); !exists {
+		t.Fatalf("order %q was fulfilled before its policy was satisfied", orderNum)
+	}
+
+	if err := o.UpdateOrders("carol", "1h", users, labels); err != nil {
+		t.Fatalf("UpdateOrders(carol): %v", err)
+	}
+	spy.mu.Lock()
+	satisfiedCount := len(spy.policySatisfied)
+	spy.mu.Unlock()
+	if satisfiedCount != 1 || spy.policySatisfied[0] != orderNum {
+		t.Fatalf("policySatisfied = %v, want [%q]", spy.policySatisfied, orderNum)
+	}
+
+	if err := o.FulfillOrders("alice", owners, labels); err != nil {
+		t.Fatalf("FulfillOrders: %v", err)
+	}
+	if _, exists, _ := o.store.Get(orderNum); exists {
+		t.Fatalf("order %q was not fulfilled once its policy was satisfied", orderNum)
+	}
+	spy.mu.Lock()
+	defer spy.mu.Unlock()
+	if len(spy.orderFulfilled) != 1 || spy.orderFulfilled[0] != orderNum {
+		t.Fatalf("orderFulfilled = %v, want [%q]", spy.orderFulfilled, orderNum)
+	}
+}
+
+// TestUpdateOrdersForbiddenOwner checks that a ForbiddenOwners entry is
+// never recorded as a delegation.
+func TestUpdateOrdersForbiddenOwner(t *testing.T) {
+	o := NewOrderer(notifier.NoOp{})
+	defer o.Close()
+	audit := &auditSpy{}
+	o.Audit = audit
+	users := []string{"alice"}
+	labels := []string{"prod"}
+	owners := []string{"bob", "carol"}
+	policy := Policy{ForbiddenOwners: []string{"bob"}}
+
+	orderNum, err := o.CreateOrderWithPolicy("creator", GenerateNum(), time.Now(), time.Hour, nil, owners, users, labels, 0, policy)
+	if err != nil {
+		t.Fatalf("CreateOrderWithPolicy: %v", err)
+	}
+
+	if err := o.UpdateOrders("bob", "1h", users, labels); err != nil {
+		t.Fatalf("UpdateOrders(bob): %v", err)
+	}
+	ord, _, err := o.store.Get(orderNum)
+	if err != nil {
+		t.Fatalf("store.Get(%q): %v", orderNum, err)
+	}
+	if containsOwner(ord.OwnersDelegated, "bob") {
+		t.Fatalf("OwnersDelegated = %v, forbidden owner bob must not appear", ord.OwnersDelegated)
+	}
+
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	var rejected []orderaudit.Event
+	for _, e := range audit.events {
+		if e.Action == orderaudit.ActionRejected {
+			rejected = append(rejected, e)
+		}
+	}
+	if len(rejected) != 1 || rejected[0].Actor != "bob" || rejected[0].OrderNum != orderNum {
+		t.Fatalf("rejected audit events = %+v, want a single ActionRejected event for bob on %q", rejected, orderNum)
+	}
+}
+
+// TestCancelOrderAudits checks that CancelOrder records an audit event
+// for the cancellation, rather than leaving no trail of who canceled an
+// order or when.
+func TestCancelOrderAudits(t *testing.T) {
+	o := NewOrderer(notifier.NoOp{})
+	defer o.Close()
+	audit := &auditSpy{}
+	o.Audit = audit
+
+	orderNum, err := o.CreateOrder("creator", GenerateNum(), time.Now(), time.Hour, nil, []string{"bob"}, []string{"alice"}, []string{"prod"}, 0)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	ok, err := o.CancelOrder(orderNum)
+	if err != nil || !ok {
+		t.Fatalf("CancelOrder = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	var canceled []orderaudit.Event
+	for _, e := range audit.events {
+		if e.Action == orderaudit.ActionCanceled {
+			canceled = append(canceled, e)
+		}
+	}
+	if len(canceled) != 1 || canceled[0].OrderNum != orderNum || canceled[0].Actor != "creator" {
+		t.Fatalf("canceled audit events = %+v, want a single ActionCanceled event for %q", canceled, orderNum)
+	}
+}
+
+// TestNewOrdererWithStoreRearmsExpiration checks that an order already
+// in the store when an Orderer is constructed - as if this process had
+// just restarted, or a peer sharing the store had created it - still
+// expires, instead of sitting forever until something else touches it.
+func TestNewOrdererWithStoreRearmsExpiration(t *testing.T) {
+	store := NewMemStore()
+	ord := CreateOrder("creator", GenerateNum(), time.Now().Add(-2*time.Hour), time.Hour, nil, []string{"bob"}, []string{"alice"}, []string{"prod"}, 0)
+	if err := store.Put(ord.Num, ord); err != nil {
+		t.Fatalf("store.Put: %v", err)
+	}
+
+	spy := &policySpy{}
+	o := NewOrdererWithStore(store, spy)
+	defer o.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		spy.mu.Lock()
+		expired := len(spy.orderExpired) > 0
+		spy.mu.Unlock()
+		if expired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("order %q was never expired", ord.Num)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, exists, err := store.Get(ord.Num); err != nil || exists {
+		t.Fatalf("store.Get(%q) = (_, %v, %v), want (_, false, nil)", ord.Num, exists, err)
+	}
+}
@@ -0,0 +1,69 @@
+package order
+
+// Policy describes the quorum an order's delegations must meet before
+// it can be fulfilled. The zero Policy reproduces the historical
+// behavior of requiring every one of Order.Owners to delegate.
+type Policy struct {
+	// Threshold is how many distinct owners must delegate before the
+	// order is fulfillable. Zero means "require every owner", i.e.
+	// len(Order.Owners).
+	Threshold int
+
+	// RequiredOwners must all appear in OwnersDelegated regardless of
+	// Threshold.
+	RequiredOwners []string
+
+	// ForbiddenOwners may never delegate toward this order.
+	ForbiddenOwners []string
+
+	// OwnerGroups maps an owner to the group tag used by
+	// MinDistinctGroups. An owner absent from the map counts as its
+	// own singleton group.
+	OwnerGroups map[string]string
+
+	// MinDistinctGroups, if non-zero, requires OwnersDelegated to span
+	// at least this many distinct groups per OwnerGroups.
+	MinDistinctGroups int
+}
+
+func (p Policy) groupOf(owner string) string {
+	if group, ok := p.OwnerGroups[owner]; ok {
+		return group
+	}
+	return owner
+}
+
+// Forbids reports whether owner is barred from delegating toward an
+// order under this policy.
+func (p Policy) Forbids(owner string) bool {
+	return containsOwner(p.ForbiddenOwners, owner)
+}
+
+// Satisfied reports whether ord.OwnersDelegated currently meets p.
+func (p Policy) Satisfied(ord Order) bool {
+	threshold := p.Threshold
+	if threshold == 0 {
+		threshold = len(ord.Owners)
+	}
+	if len(ord.OwnersDelegated) < threshold {
+		return false
+	}
+
+	for _, required := range p.RequiredOwners {
+		if !containsOwner(ord.OwnersDelegated, required) {
+			return false
+		}
+	}
+
+	if p.MinDistinctGroups > 0 {
+		groups := make(map[string]bool)
+		for _, owner := range ord.OwnersDelegated {
+			groups[p.groupOf(owner)] = true
+		}
+		if len(groups) < p.MinDistinctGroups {
+			return false
+		}
+	}
+
+	return true
+}
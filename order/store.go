@@ -0,0 +1,45 @@
+package order
+
+import "context"
+
+// Event describes a single change to the store, delivered to Watch
+// subscribers so that peers sharing a backend learn when an order is
+// created, updated, or removed by someone else.
+type Event struct {
+	OrderNum string
+	Order    Order
+	Deleted  bool
+}
+
+// Cipher encrypts and decrypts order records before they touch disk or
+// the network, using the same key material as the rest of RedOctober's
+// vault. Implementations that never leave memory (MemStore) don't need
+// one.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// OrderStore persists Orders keyed by order number. It lives in this
+// package, rather than alongside its concrete implementations in
+// orderstore, so that orderstore can depend on order for the Order type
+// without order depending back on orderstore.
+type OrderStore interface {
+	// Get returns the order for orderNum, or ok == false if it isn't
+	// present.
+	Get(orderNum string) (ord Order, ok bool, err error)
+
+	// Put creates or replaces the order stored under orderNum.
+	Put(orderNum string, ord Order) error
+
+	// Delete removes orderNum. It is not an error if it doesn't exist.
+	Delete(orderNum string) error
+
+	// List returns every order currently in the store.
+	List() ([]Order, error)
+
+	// Watch returns a channel of Events for changes made by any
+	// client of the store, including other processes sharing the same
+	// backend. The channel is closed when ctx is done.
+	Watch(ctx context.Context) <-chan Event
+}
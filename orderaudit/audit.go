@@ -0,0 +1,47 @@
+// Package orderaudit defines a pluggable, append-only audit trail for
+// order lifecycle transitions, the kind of record regulators typically
+// require of a key-escrow system.
+//
+// Copyright (c) 2016 CloudFlare, Inc.
+package orderaudit
+
+import "time"
+
+// Action identifies which order lifecycle transition an Event records.
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionDelegated Action = "delegated"
+	ActionFulfilled Action = "fulfilled"
+	ActionExpired   Action = "expired"
+	ActionRejected  Action = "rejected"
+	ActionCanceled  Action = "canceled"
+)
+
+// Event is a structured record of a single state transition in an
+// order's lifecycle.
+type Event struct {
+	OrderNum   string    `json:"order_id"`
+	Actor      string    `json:"actor"`
+	Action     Action    `json:"action"`
+	Labels     []string  `json:"labels"`
+	Delegatees []string  `json:"delegatees,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	// RemainingNeeded is how many more distinct delegations the order
+	// requires before it can be fulfilled, 0 once satisfied.
+	RemainingNeeded int `json:"remaining_delegations_needed"`
+}
+
+// Sink records audit Events somewhere durable. Implementations must be
+// safe for concurrent use.
+type Sink interface {
+	Record(Event) error
+}
+
+// NoOp discards every event. It is the default for Orderers that don't
+// configure an audit trail.
+type NoOp struct{}
+
+func (NoOp) Record(Event) error { return nil }
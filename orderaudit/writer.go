@@ -0,0 +1,21 @@
+package orderaudit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Writer records each Event as a JSON line written to W, e.g. an
+// append-only log file. It is safe for concurrent use.
+type Writer struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+func (a *Writer) Record(e Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return json.NewEncoder(a.W).Encode(e)
+}
@@ -0,0 +1,42 @@
+package orderaudit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWriterRecordsOneJSONLinePerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{W: &buf}
+
+	events := []Event{
+		{OrderNum: "a", Actor: "alice", Action: ActionCreated, Timestamp: time.Now()},
+		{OrderNum: "b", Actor: "bob", Action: ActionFulfilled, Timestamp: time.Now()},
+	}
+	for _, e := range events {
+		if err := w.Record(e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	var got []Event
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Unmarshal line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("got %d lines, want %d", len(got), len(events))
+	}
+	for i, e := range events {
+		if got[i].OrderNum != e.OrderNum || got[i].Actor != e.Actor || got[i].Action != e.Action {
+			t.Fatalf("line %d = %+v, want %+v", i, got[i], e)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package orderstore provides pluggable, persistent OrderStore
+// backends - BoltDB and Consul KV - for orders that must outlive a
+// single process. The OrderStore interface itself, along with Event
+// and Cipher, lives in the order package so that order never has to
+// import orderstore back.
+//
+// Copyright (c) 2016 CloudFlare, Inc.
+package orderstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/cloudflare/redoctober/order"
+)
+
+var ordersBucket = []byte("orders")
+
+// BoltStore persists orders to the same on-disk vault path used
+// elsewhere in RedOctober, encrypting each record with cipher before it
+// touches disk so a stolen database file doesn't leak delegatee lists.
+// It is single-node, and its Watch reports no changes at all, not even
+// those made through this BoltStore instance.
+type BoltStore struct {
+	db     *bolt.DB
+	cipher order.Cipher
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB file at path and
+// returns a BoltStore that encrypts records with cipher.
+func NewBoltStore(path string, cipher order.Cipher) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("orderstore: opening %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(ordersBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("orderstore: initializing %s: %w", path, err)
+	}
+	return &BoltStore{db: db, cipher: cipher}, nil
+}
+
+// Close releases the underlying BoltDB file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) encode(ord order.Order) ([]byte, error) {
+	plaintext, err := json.Marshal(ord)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Encrypt(plaintext)
+}
+
+func (s *BoltStore) decode(ciphertext []byte) (order.Order, error) {
+	var ord order.Order
+	plaintext, err := s.cipher.Decrypt(ciphertext)
+	if err != nil {
+		return ord, err
+	}
+	err = json.Unmarshal(plaintext, &ord)
+	return ord, err
+}
+
+func (s *BoltStore) Get(orderNum string) (ord order.Order, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(ordersBucket).Get([]byte(orderNum))
+		if v == nil {
+			return nil
+		}
+		ok = true
+		ord, err = s.decode(v)
+		return err
+	})
+	return ord, ok, err
+}
+
+func (s *BoltStore) Put(orderNum string, ord order.Order) error {
+	enc, err := s.encode(ord)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(ordersBucket).Put([]byte(orderNum), enc)
+	})
+}
+
+func (s *BoltStore) Delete(orderNum string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(ordersBucket).Delete([]byte(orderNum))
+	})
+}
+
+func (s *BoltStore) List() ([]order.Order, error) {
+	var out []order.Order
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ordersBucket).ForEach(func(_, v []byte) error {
+			ord, err := s.decode(v)
+			if err != nil {
+				return err
+			}
+			out = append(out, ord)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// Watch has no peers to learn about, so it just closes its channel when
+// ctx is done.
+func (s *BoltStore) Watch(ctx context.Context) <-chan order.Event {
+	ch := make(chan order.Event)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,79 @@
+package orderstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/redoctober/order"
+)
+
+func TestBoltStoreGetPutDeleteListRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	s, err := NewBoltStore(path, testCipher(t))
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer s.Close()
+
+	want := order.Order{Num: "abc", Creator: "alice", TimeRequested: time.Now().Truncate(time.Second)}
+	if err := s.Put(want.Num, want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := s.Get(want.Num)
+	if err != nil || !ok {
+		t.Fatalf("Get(%q) = (_, %v, %v), want (_, true, nil)", want.Num, ok, err)
+	}
+	if !got.TimeRequested.Equal(want.TimeRequested) || got.Creator != want.Creator {
+		t.Fatalf("Get(%q) = %+v, want %+v", want.Num, got, want)
+	}
+
+	all, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != 1 || all[0].Num != want.Num {
+		t.Fatalf("List() = %+v, want a single order %q", all, want.Num)
+	}
+
+	if err := s.Delete(want.Num); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, err := s.Get(want.Num); err != nil || ok {
+		t.Fatalf("Get(%q) after Delete = (_, %v, %v), want (_, false, nil)", want.Num, ok, err)
+	}
+}
+
+// TestNewBoltStoreReopensExistingFile checks that the orders bucket
+// created by NewBoltStore survives closing and reopening the same file,
+// since that's exactly what happens across a process restart.
+func TestNewBoltStoreReopensExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	cipher := testCipher(t)
+
+	s1, err := NewBoltStore(path, cipher)
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	if err := s1.Put("abc", order.Order{Num: "abc", Creator: "alice"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s1.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s2, err := NewBoltStore(path, cipher)
+	if err != nil {
+		t.Fatalf("reopening NewBoltStore: %v", err)
+	}
+	defer s2.Close()
+
+	got, ok, err := s2.Get("abc")
+	if err != nil || !ok {
+		t.Fatalf("Get(abc) after reopen = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.Creator != "alice" {
+		t.Fatalf("Get(abc).Creator after reopen = %q, want %q", got.Creator, "alice")
+	}
+}
@@ -0,0 +1,200 @@
+package orderstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/redoctober/order"
+	"github.com/hashicorp/consul/api"
+)
+
+// watchRetryDelay is how long Watch waits before retrying s.kv.List
+// after an error, so a Consul outage doesn't turn into a busy loop.
+const watchRetryDelay = time.Second
+
+// casRetries bounds how many times Put retries a CAS write after losing
+// a race against a concurrent writer, before giving up and reporting
+// the conflict to the caller.
+const casRetries = 5
+
+// consulKV is the subset of *api.KV that ConsulStore needs, narrowed to
+// an interface so tests can exercise Put's CAS/retry logic against a
+// fake instead of a live Consul agent.
+type consulKV interface {
+	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
+	Put(p *api.KVPair, w *api.WriteOptions) (*api.WriteMeta, error)
+	CAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error)
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+}
+
+// ConsulStore persists orders under a Consul KV prefix, so that a
+// cluster of RedOctober nodes can share pending orders and learn via
+// Watch when a peer fulfills or updates one.
+type ConsulStore struct {
+	kv     consulKV
+	prefix string
+	cipher order.Cipher
+}
+
+// NewConsulStore returns a ConsulStore that stores orders under prefix
+// (a trailing "/" is added if missing) in the KV store reachable
+// through client, encrypting each record with cipher.
+func NewConsulStore(client *api.Client, prefix string, cipher order.Cipher) *ConsulStore {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &ConsulStore{kv: client.KV(), prefix: prefix, cipher: cipher}
+}
+
+func (s *ConsulStore) key(orderNum string) string {
+	return s.prefix + orderNum
+}
+
+func (s *ConsulStore) encode(ord order.Order) ([]byte, error) {
+	plaintext, err := json.Marshal(ord)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Encrypt(plaintext)
+}
+
+func (s *ConsulStore) decode(ciphertext []byte) (order.Order, error) {
+	var ord order.Order
+	plaintext, err := s.cipher.Decrypt(ciphertext)
+	if err != nil {
+		return ord, err
+	}
+	err = json.Unmarshal(plaintext, &ord)
+	return ord, err
+}
+
+func (s *ConsulStore) Get(orderNum string) (order.Order, bool, error) {
+	pair, _, err := s.kv.Get(s.key(orderNum), nil)
+	if err != nil {
+		return order.Order{}, false, fmt.Errorf("orderstore: consul get %s: %w", orderNum, err)
+	}
+	if pair == nil {
+		return order.Order{}, false, nil
+	}
+	ord, err := s.decode(pair.Value)
+	return ord, true, err
+}
+
+// Put writes ord using a compare-and-swap, retrying against the latest
+// ModifyIndex on conflict, rather than a blind overwrite. Plain Put
+// would let two nodes racing on the same order (each having listed a
+// stale copy) silently clobber one another's delegation; CAS makes that
+// race fail loudly instead of losing an update.
+func (s *ConsulStore) Put(orderNum string, ord order.Order) error {
+	enc, err := s.encode(ord)
+	if err != nil {
+		return err
+	}
+	key := s.key(orderNum)
+	for attempt := 0; attempt < casRetries; attempt++ {
+		pair, _, err := s.kv.Get(key, nil)
+		if err != nil {
+			return fmt.Errorf("orderstore: consul get %s: %w", orderNum, err)
+		}
+		var modifyIndex uint64
+		if pair != nil {
+			modifyIndex = pair.ModifyIndex
+		}
+		ok, _, err := s.kv.CAS(&api.KVPair{Key: key, Value: enc, ModifyIndex: modifyIndex}, nil)
+		if err != nil {
+			return fmt.Errorf("orderstore: consul put %s: %w", orderNum, err)
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("orderstore: consul put %s: %d conflicting writes", orderNum, casRetries)
+}
+
+func (s *ConsulStore) Delete(orderNum string) error {
+	if _, err := s.kv.Delete(s.key(orderNum), nil); err != nil {
+		return fmt.Errorf("orderstore: consul delete %s: %w", orderNum, err)
+	}
+	return nil
+}
+
+func (s *ConsulStore) List() ([]order.Order, error) {
+	pairs, _, err := s.kv.List(s.prefix, nil)
+	if err != nil {
+		return nil, fmt.Errorf("orderstore: consul list %s: %w", s.prefix, err)
+	}
+	out := make([]order.Order, 0, len(pairs))
+	for _, pair := range pairs {
+		ord, err := s.decode(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ord)
+	}
+	return out, nil
+}
+
+// Watch polls Consul's blocking query API for changes under the store's
+// prefix and emits an Event for each key whose ModifyIndex advances,
+// including deletions.
+func (s *ConsulStore) Watch(ctx context.Context) <-chan order.Event {
+	ch := make(chan order.Event)
+	go func() {
+		defer close(ch)
+		seen := make(map[string]uint64)
+		var waitIndex uint64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			opts := &api.QueryOptions{WaitIndex: waitIndex}
+			pairs, meta, err := s.kv.List(s.prefix, opts.WithContext(ctx))
+			if err != nil {
+				select {
+				case <-time.After(watchRetryDelay):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			waitIndex = meta.LastIndex
+
+			present := make(map[string]bool, len(pairs))
+			for _, pair := range pairs {
+				key := strings.TrimPrefix(pair.Key, s.prefix)
+				present[key] = true
+				if seen[key] == pair.ModifyIndex {
+					continue
+				}
+				seen[key] = pair.ModifyIndex
+				ord, err := s.decode(pair.Value)
+				if err != nil {
+					continue
+				}
+				select {
+				case ch <- order.Event{OrderNum: key, Order: ord}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			for key := range seen {
+				if !present[key] {
+					delete(seen, key)
+					select {
+					case ch <- order.Event{OrderNum: key, Deleted: true}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,109 @@
+package orderstore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/redoctober/order"
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeKV is a minimal in-memory consulKV for exercising ConsulStore's
+// CAS/retry logic without a live Consul agent.
+type fakeKV struct {
+	pairs map[string]*api.KVPair
+
+	// casConflicts forces this many CAS calls to report failure (as if
+	// a concurrent writer had won the race) before one succeeds.
+	casConflicts int
+}
+
+func (f *fakeKV) Get(key string, _ *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	return f.pairs[key], &api.QueryMeta{}, nil
+}
+
+func (f *fakeKV) Put(p *api.KVPair, _ *api.WriteOptions) (*api.WriteMeta, error) {
+	f.pairs[p.Key] = &api.KVPair{Key: p.Key, Value: p.Value, ModifyIndex: p.ModifyIndex + 1}
+	return nil, nil
+}
+
+func (f *fakeKV) CAS(p *api.KVPair, _ *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if f.casConflicts > 0 {
+		f.casConflicts--
+		return false, nil, nil
+	}
+	existing := f.pairs[p.Key]
+	if existing != nil && existing.ModifyIndex != p.ModifyIndex {
+		return false, nil, nil
+	}
+	f.pairs[p.Key] = &api.KVPair{Key: p.Key, Value: p.Value, ModifyIndex: p.ModifyIndex + 1}
+	return true, nil, nil
+}
+
+func (f *fakeKV) Delete(key string, _ *api.WriteOptions) (*api.WriteMeta, error) {
+	delete(f.pairs, key)
+	return nil, nil
+}
+
+func (f *fakeKV) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var out api.KVPairs
+	for _, pair := range f.pairs {
+		out = append(out, pair)
+	}
+	return out, &api.QueryMeta{}, nil
+}
+
+func testCipher(t *testing.T) order.Cipher {
+	t.Helper()
+	c, err := order.NewAESCipher(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("NewAESCipher: %v", err)
+	}
+	return c
+}
+
+func TestConsulStorePutRetriesOnConflict(t *testing.T) {
+	kv := &fakeKV{pairs: make(map[string]*api.KVPair), casConflicts: 2}
+	s := &ConsulStore{kv: kv, prefix: "orders/", cipher: testCipher(t)}
+
+	ord := order.Order{Num: "abc", Creator: "alice"}
+	if err := s.Put("abc", ord); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := s.Get("abc")
+	if err != nil || !ok {
+		t.Fatalf("Get(abc) = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.Creator != "alice" {
+		t.Fatalf("Get(abc).Creator = %q, want %q", got.Creator, "alice")
+	}
+}
+
+func TestConsulStorePutGivesUpAfterTooManyConflicts(t *testing.T) {
+	kv := &fakeKV{pairs: make(map[string]*api.KVPair), casConflicts: casRetries}
+	s := &ConsulStore{kv: kv, prefix: "orders/", cipher: testCipher(t)}
+
+	if err := s.Put("abc", order.Order{Num: "abc"}); err == nil {
+		t.Fatal("Put should have failed after exhausting its CAS retries")
+	}
+}
+
+func TestConsulStoreGetPutRoundTrip(t *testing.T) {
+	kv := &fakeKV{pairs: make(map[string]*api.KVPair)}
+	s := &ConsulStore{kv: kv, prefix: "orders/", cipher: testCipher(t)}
+
+	want := order.Order{Num: "abc", Creator: "alice", TimeRequested: time.Now().Truncate(time.Second)}
+	if err := s.Put("abc", want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := s.Get("abc")
+	if err != nil || !ok {
+		t.Fatalf("Get(abc) = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if !got.TimeRequested.Equal(want.TimeRequested) || got.Creator != want.Creator {
+		t.Fatalf("Get(abc) = %+v, want %+v", got, want)
+	}
+}